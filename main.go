@@ -1,34 +1,19 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
 	"strings"
-)
-
-// Table represents an iptables table (e.g., filter, nat).
-type Table struct {
-	Name   string
-	Chains []*Chain
-	Rules  []*Rule
-}
-
-// Chain represents a single iptables chain within a table.
-type Chain struct {
-	Name    string
-	Policy  string // e.g., ACCEPT, DROP
-	Counter string // [packets:bytes]
-}
 
-// Rule represents a single iptables rule.
-type Rule struct {
-	ChainName string
-	Rule      string // The full rule string (e.g., "-A DOCKER-USER -j RETURN")
-}
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+	"github.com/masudur-rahman/iptables-parser/pkg/pipeline"
+	"github.com/masudur-rahman/iptables-parser/pkg/source"
+)
 
 // stringSliceValue is a custom type that implements the flag.Value interface.
 type stringSliceValue []string
@@ -42,156 +27,182 @@ func (s *stringSliceValue) Set(value string) error {
 	return nil
 }
 
-// hasString checks if a string exists in a slice.
-func hasString(slice []string, s string) bool {
-	for _, val := range slice {
-		if val == s {
-			return true
-		}
-	}
-	return false
-}
-
-// readAndParse reads iptables-save output from an io.Reader and returns a map of tables.
-func readAndParse(r io.Reader) (map[string]*Table, error) {
-	tables := make(map[string]*Table)
-	var currentTable *Table
+// repeatableValue collects one entry per flag occurrence, unlike
+// stringSliceValue which replaces its contents on every Set call. It backs
+// --transform, which is meant to be passed multiple times to build a chain.
+type repeatableValue []string
 
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if len(line) == 0 {
-			continue
-		}
-
-		switch {
-		case strings.HasPrefix(line, "*"):
-			tableName := line[1:]
-			currentTable = &Table{Name: tableName}
-			tables[tableName] = currentTable
-
-		case strings.HasPrefix(line, ":"):
-			if currentTable != nil {
-				parts := strings.Fields(line)
-				chain := &Chain{Name: parts[0][1:], Policy: parts[1], Counter: parts[2]}
-				currentTable.Chains = append(currentTable.Chains, chain)
-			}
-
-		case strings.HasPrefix(line, "-"):
-			if currentTable != nil {
-				parts := strings.Fields(line)
-				rule := &Rule{ChainName: parts[1], Rule: line}
-				currentTable.Rules = append(currentTable.Rules, rule)
-			}
+func (r *repeatableValue) String() string {
+	return strings.Join(*r, ", ")
+}
 
-		case strings.HasPrefix(line, "COMMIT"):
-			currentTable = nil
+func (r *repeatableValue) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
 
-		case strings.HasPrefix(line, "#"):
-			continue
-		}
+// toSet converts a comma-separated stringSliceValue into a lookup set. An
+// empty slice yields a nil set, which FilterHandler treats as "allow all".
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading input: %w", err)
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
 	}
-	return tables, nil
+	return set
 }
 
-// printRules writes the filtered tables in iptables-restore format to an io.Writer.
-func printRules(w io.Writer, tables map[string]*Table) {
-	for _, table := range tables {
-		fmt.Fprintf(w, "*%s\n", table.Name)
-
-		for _, chain := range table.Chains {
-			fmt.Fprintf(w, ":%s %s %s\n", chain.Name, chain.Policy, chain.Counter)
+// buildTransform wraps next with the handler named by spec, which is either
+// a bare name ("dedup") or "name=arg" ("rename=OLD:NEW;OLD2:NEW2"). Mappings
+// are ";"-separated, not ","-separated, because a target like "LOG,ACCEPT"
+// legitimately contains a comma.
+func buildTransform(spec string, next pipeline.Handler) (pipeline.Handler, error) {
+	name, arg, _ := strings.Cut(spec, "=")
+	switch name {
+	case "rename":
+		renames := make(map[string]string)
+		for _, pair := range strings.Split(arg, ";") {
+			old, new, ok := strings.Cut(pair, ":")
+			if !ok {
+				return nil, fmt.Errorf("--transform rename: invalid mapping %q, want OLD:NEW", pair)
+			}
+			renames[old] = new
 		}
+		return pipeline.NewRenameChainHandler(renames, next), nil
+
+	case "rewrite-jump":
+		mapping := make(map[string]string)
+		for _, pair := range strings.Split(arg, ";") {
+			old, new, ok := strings.Cut(pair, ":")
+			if !ok {
+				return nil, fmt.Errorf("--transform rewrite-jump: invalid mapping %q, want OLD:NEW", pair)
+			}
+			mapping[old] = new
+		}
+		return pipeline.NewRewriteJumpHandler(mapping, next), nil
 
-		for _, rule := range table.Rules {
-			fmt.Fprintln(w, rule.Rule)
+	case "comment":
+		if arg == "" {
+			return nil, fmt.Errorf("--transform comment requires text, e.g. comment=managed-by-tool")
 		}
+		return pipeline.NewPrefixCommentHandler(arg, next), nil
+
+	case "dedup":
+		return pipeline.NewDedupHandler(next), nil
 
-		fmt.Fprintln(w, "COMMIT")
-		fmt.Fprintln(w)
+	default:
+		return nil, fmt.Errorf("unknown --transform %q", name)
 	}
 }
 
 func main() {
 	inputFilePath := flag.String("input", "", "Path to the input iptables-save file")
 	outputFilePath := flag.String("output", "", "Path to the output file (defaults to stdout)")
+	sourceType := flag.String("source", "save", `Where to read iptables data from: "save" (an iptables-save file given via --input) or "netlink" (read directly from the kernel)`)
 	var chainsToProcess stringSliceValue
 	flag.Var(&chainsToProcess, "chains", "Comma-separated list of chains to process (e.g., INPUT,OUTPUT). Defaults to all chains.")
 	var tablesToProcess stringSliceValue
 	flag.Var(&tablesToProcess, "tables", "Comma-separated list of tables to process (e.g., filter,nat). Defaults to all tables.")
+	withCounters := flag.Bool("with-counters", false, "Prefix each rule with its [packets:bytes] counters, like \"iptables-save -c\"")
+	withComments := flag.Bool("with-comments", false, "Reproduce the input's \"#\" comment lines")
+	var transforms repeatableValue
+	flag.Var(&transforms, "transform", `Apply a transform to the rule stream; may be repeated, applied in the order given. One of: rename=OLD:NEW[;OLD2:NEW2], rewrite-jump=OLD:NEW[;OLD2:NEW2], comment=TEXT, dedup`)
 	flag.Parse()
 
-	if *inputFilePath == "" {
-		log.Fatal("Error: --input flag is required")
+	var output io.Writer
+	if *outputFilePath != "" {
+		outputFile, err := os.Create(*outputFilePath)
+		if err != nil {
+			log.Fatalf("failed to create output file '%s': %v", *outputFilePath, err)
+		}
+		defer outputFile.Close()
+		output = outputFile
+	} else {
+		output = os.Stdout
 	}
 
-	inputFile, err := os.Open(*inputFilePath)
-	if err != nil {
-		log.Fatalf("failed to open input file '%s': %v", *inputFilePath, err)
+	// Build the handler chain from the sink backward: the FilterHandler
+	// replaces the tool's old map-filtering logic, and each --transform
+	// wraps it so the first one given runs closest to the source.
+	var handler pipeline.Handler = pipeline.NewPrintHandler(output, iptables.PrintOptions{WithCounters: *withCounters, WithComments: *withComments})
+	handler = pipeline.NewFilterHandler(toSet(tablesToProcess), toSet(chainsToProcess), handler)
+	for i := len(transforms) - 1; i >= 0; i-- {
+		var err error
+		handler, err = buildTransform(transforms[i], handler)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
 	}
-	defer inputFile.Close()
 
-	allTables, err := readAndParse(inputFile)
-	if err != nil {
-		log.Fatalf("failed to parse iptables data: %v", err)
-	}
+	// A "save" source is a plain text stream, so it's fed straight into the
+	// Parser and never buffered into a map. Other sources (netlink reads
+	// the kernel's rule tables directly) hand back an already-materialized
+	// snapshot, which is replayed through the same handler chain.
+	switch *sourceType {
+	case "save":
+		if *inputFilePath == "" {
+			log.Fatal("Error: --input flag is required when --source=save")
+		}
+		inputFile, err := os.Open(*inputFilePath)
+		if err != nil {
+			log.Fatalf("failed to open input file '%s': %v", *inputFilePath, err)
+		}
+		defer inputFile.Close()
 
-	finalTables := make(map[string]*Table)
-	var requestedTables []string
+		if err := pipeline.NewParser(handler).Run(inputFile); err != nil {
+			log.Fatalf("failed to process iptables data: %v", err)
+		}
 
-	// If no tables are specified, get all of them.
-	if len(tablesToProcess) > 0 {
-		requestedTables = tablesToProcess
-	} else {
-		for tableName := range allTables {
-			requestedTables = append(requestedTables, tableName)
+	case "netlink":
+		netlinkSource, err := source.NewNetlinkSource()
+		if err != nil {
+			log.Fatalf("failed to open netlink source: %v", err)
+		}
+		allTables, err := netlinkSource.Read(context.Background())
+		if err != nil {
+			log.Fatalf("failed to read iptables data: %v", err)
 		}
+		if err := emit(allTables, handler); err != nil {
+			log.Fatalf("failed to emit iptables data: %v", err)
+		}
+
+	default:
+		log.Fatalf("Error: unknown --source %q (want \"save\" or \"netlink\")", *sourceType)
 	}
+}
 
-	for _, tableName := range requestedTables {
-		if sourceTable, ok := allTables[tableName]; ok {
-			finalTable := &Table{Name: sourceTable.Name}
-
-			// If no chains are specified, get all of them.
-			var requestedChains []string
-			if len(chainsToProcess) > 0 {
-				requestedChains = chainsToProcess
-			} else {
-				for _, chain := range sourceTable.Chains {
-					requestedChains = append(requestedChains, chain.Name)
-				}
-			}
+// emit replays a fully-read snapshot through handler as Parser.Run would,
+// so sources that can't stream events directly (netlink) still go through
+// the same FilterHandler/transform chain as a streamed "save" source.
+// Tables are visited in sorted order, same as iptables.Print, so output
+// doesn't vary from run to run with Go's randomized map iteration.
+func emit(tables map[string]*iptables.Table, handler pipeline.Handler) error {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-			for _, chain := range sourceTable.Chains {
-				if hasString(requestedChains, chain.Name) {
-					finalTable.Chains = append(finalTable.Chains, chain)
-				}
+	for _, name := range names {
+		table := tables[name]
+		if err := handler.OnTable(table.Name); err != nil {
+			return err
+		}
+		for _, chain := range table.Chains {
+			if err := handler.OnChain(chain); err != nil {
+				return err
 			}
-
-			for _, rule := range sourceTable.Rules {
-				if hasString(requestedChains, rule.ChainName) {
-					finalTable.Rules = append(finalTable.Rules, rule)
-				}
+		}
+		for _, rule := range table.Rules {
+			if err := handler.OnRule(rule); err != nil {
+				return err
 			}
-
-			finalTables[finalTable.Name] = finalTable
 		}
-	}
-
-	var output io.Writer
-	if *outputFilePath != "" {
-		outputFile, err := os.Create(*outputFilePath)
-		if err != nil {
-			log.Fatalf("failed to create output file '%s': %v", *outputFilePath, err)
+		if err := handler.OnCommit(table.Name); err != nil {
+			return err
 		}
-		defer outputFile.Close()
-		output = outputFile
-	} else {
-		output = os.Stdout
 	}
-
-	printRules(output, finalTables)
+	return nil
 }
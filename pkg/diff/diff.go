@@ -0,0 +1,343 @@
+// Package diff compares two parsed iptables snapshots and produces a
+// minimal iptables-restore script to move a live system from the old
+// snapshot to the new one.
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+	"github.com/masudur-rahman/iptables-parser/pkg/rule"
+)
+
+// op is a single edit to apply to a chain's rule list.
+type op struct {
+	kind string // "delete", "insert", "append"
+	pos  int    // 1-based position, only meaningful for "insert"
+	spec string // the rule spec, without the leading "-A <chain>"/"-D <chain>"
+}
+
+// tableChange holds the changes computed for a single table.
+type tableChange struct {
+	name          string
+	createdChains []string
+	deletedChains []string
+	chainPolicy   map[string]string // chain -> new policy
+	chainOrder    []string          // chains with rule-level ops, in render order
+	ops           map[string][]op   // chain -> ordered ops
+}
+
+// Changeset is the set of changes needed to move one iptables snapshot to
+// another.
+type Changeset struct {
+	tables []*tableChange
+}
+
+// Diff compares old and new snapshots (as produced by iptables.Parse) and
+// returns the minimal Changeset that moves old to new.
+func Diff(old, new map[string]*iptables.Table) *Changeset {
+	cs := &Changeset{}
+
+	for _, tableName := range sortedTableNames(old, new) {
+		newTable, ok := new[tableName]
+		if !ok {
+			// The target snapshot dropped the table entirely; iptables-restore
+			// has no way to express "remove a table", so there is nothing to
+			// emit.
+			continue
+		}
+		oldTable := old[tableName]
+		if oldTable == nil {
+			oldTable = &iptables.Table{Name: tableName}
+		}
+
+		cs.tables = append(cs.tables, diffTable(tableName, oldTable, newTable))
+	}
+
+	return cs
+}
+
+func diffTable(name string, oldTable, newTable *iptables.Table) *tableChange {
+	tc := &tableChange{name: name, chainPolicy: map[string]string{}, ops: map[string][]op{}}
+
+	oldChains := chainsByName(oldTable.Chains)
+	newChains := chainsByName(newTable.Chains)
+
+	for _, name := range sortedChainNames(newChains) {
+		nc := newChains[name]
+		if oc, ok := oldChains[name]; !ok {
+			tc.createdChains = append(tc.createdChains, name)
+		} else if oc.Policy != nc.Policy && nc.Policy != "-" {
+			tc.chainPolicy[name] = nc.Policy
+		}
+	}
+
+	for _, name := range sortedChainNames(oldChains) {
+		if _, ok := newChains[name]; !ok {
+			tc.deletedChains = append(tc.deletedChains, name)
+		}
+	}
+
+	oldRules := rulesByChain(oldTable.Rules)
+	newRules := rulesByChain(newTable.Rules)
+
+	chainSet := map[string]bool{}
+	for name := range oldRules {
+		chainSet[name] = true
+	}
+	for name := range newRules {
+		chainSet[name] = true
+	}
+
+	var chainNames []string
+	for name := range chainSet {
+		chainNames = append(chainNames, name)
+	}
+	sort.Strings(chainNames)
+
+	for _, name := range chainNames {
+		ops := diffChainRules(oldRules[name], newRules[name])
+		if len(ops) == 0 {
+			continue
+		}
+		tc.ops[name] = ops
+		tc.chainOrder = append(tc.chainOrder, name)
+	}
+
+	return tc
+}
+
+// diffChainRules computes the minimal set of -D/-A/-I operations to turn
+// oldRules into newRules, using an LCS over the rule sequence so that
+// unmodified rules are left untouched and their relative order is
+// preserved.
+func diffChainRules(oldRules, newRules []*iptables.Rule) []op {
+	matched := longestCommonSubsequence(oldRules, newRules)
+
+	matchedOldIdx := make(map[int]bool, len(matched))
+	matchedNewIdx := make(map[int]int, len(matched)) // newIdx -> oldIdx
+	for _, pair := range matched {
+		matchedOldIdx[pair.oldIdx] = true
+		matchedNewIdx[pair.newIdx] = pair.oldIdx
+	}
+
+	var ops []op
+
+	// Deletions: old rules that have no counterpart in the new sequence,
+	// emitted first so the remaining sequence equals the matched subsequence.
+	for i, r := range oldRules {
+		if !matchedOldIdx[i] {
+			ops = append(ops, op{kind: "delete", spec: ruleSpec(r.Rule)})
+		}
+	}
+
+	// Insertions: walk the new sequence, tracking how long the
+	// (conceptual) post-deletion list has grown so inserts land at the
+	// right index.
+	currentLen := len(matched)
+	pos := 0
+	for j, r := range newRules {
+		if _, ok := matchedNewIdx[j]; ok {
+			pos++
+			continue
+		}
+		spec := ruleSpec(r.Rule)
+		if pos == currentLen {
+			ops = append(ops, op{kind: "append", spec: spec})
+		} else {
+			ops = append(ops, op{kind: "insert", pos: pos + 1, spec: spec})
+		}
+		currentLen++
+		pos++
+	}
+
+	return ops
+}
+
+type lcsPair struct {
+	oldIdx, newIdx int
+}
+
+// longestCommonSubsequence returns, in increasing order, the index pairs of
+// rules that are semantically equal between oldRules and newRules and can
+// be kept in place.
+func longestCommonSubsequence(oldRules, newRules []*iptables.Rule) []lcsPair {
+	n, m := len(oldRules), len(newRules)
+	if n == 0 || m == 0 {
+		return nil
+	}
+
+	equal := make([][]bool, n)
+	for i := range equal {
+		equal[i] = make([]bool, m)
+		for j := range equal[i] {
+			equal[i][j] = rulesEqual(oldRules[i], newRules[j])
+		}
+	}
+
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if equal[i][j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else if dp[i+1][j] >= dp[i][j+1] {
+				dp[i][j] = dp[i+1][j]
+			} else {
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+
+	var pairs []lcsPair
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case equal[i][j]:
+			pairs = append(pairs, lcsPair{oldIdx: i, newIdx: j})
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+// rulesEqual compares two raw iptables-save rule lines for semantic
+// equivalence, falling back to a literal string comparison if either side
+// fails to parse.
+func rulesEqual(a, b *iptables.Rule) bool {
+	ra, errA := rule.Parse(a.Rule)
+	rb, errB := rule.Parse(b.Rule)
+	if errA != nil || errB != nil {
+		return a.Rule == b.Rule
+	}
+	return ra.Equal(rb)
+}
+
+// ruleSpec strips the leading "-A <chain>" (or "-I <chain> ...") from a raw
+// rule line, leaving just the match/target spec to append after a
+// different action and chain.
+func ruleSpec(raw string) string {
+	fields := strings.Fields(raw)
+	if len(fields) <= 2 {
+		return ""
+	}
+	return strings.Join(fields[2:], " ")
+}
+
+func chainsByName(chains []*iptables.Chain) map[string]*iptables.Chain {
+	m := make(map[string]*iptables.Chain, len(chains))
+	for _, c := range chains {
+		m[c.Name] = c
+	}
+	return m
+}
+
+func rulesByChain(rules []*iptables.Rule) map[string][]*iptables.Rule {
+	m := map[string][]*iptables.Rule{}
+	for _, r := range rules {
+		m[r.ChainName] = append(m[r.ChainName], r)
+	}
+	return m
+}
+
+func sortedChainNames(chains map[string]*iptables.Chain) []string {
+	names := make([]string, 0, len(chains))
+	for name := range chains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedTableNames(old, new map[string]*iptables.Table) []string {
+	set := map[string]bool{}
+	for name := range old {
+		set[name] = true
+	}
+	for name := range new {
+		set[name] = true
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render writes the changeset as an iptables-restore --noflush script.
+func (cs *Changeset) Render(w io.Writer) error {
+	for _, tc := range cs.tables {
+		if len(tc.createdChains) == 0 && len(tc.deletedChains) == 0 &&
+			len(tc.chainPolicy) == 0 && len(tc.chainOrder) == 0 {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "*%s\n", tc.name); err != nil {
+			return err
+		}
+
+		for _, chain := range tc.createdChains {
+			if _, err := fmt.Fprintf(w, ":%s - [0:0]\n", chain); err != nil {
+				return err
+			}
+		}
+
+		for _, chain := range tc.deletedChains {
+			if _, err := fmt.Fprintf(w, "-F %s\n", chain); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "-X %s\n", chain); err != nil {
+				return err
+			}
+		}
+
+		policyChains := make([]string, 0, len(tc.chainPolicy))
+		for chain := range tc.chainPolicy {
+			policyChains = append(policyChains, chain)
+		}
+		sort.Strings(policyChains)
+		for _, chain := range policyChains {
+			if _, err := fmt.Fprintf(w, "-P %s %s\n", chain, tc.chainPolicy[chain]); err != nil {
+				return err
+			}
+		}
+
+		for _, chain := range tc.chainOrder {
+			for _, o := range tc.ops[chain] {
+				switch o.kind {
+				case "delete":
+					if _, err := fmt.Fprintf(w, "-D %s %s\n", chain, o.spec); err != nil {
+						return err
+					}
+				case "append":
+					if _, err := fmt.Fprintf(w, "-A %s %s\n", chain, o.spec); err != nil {
+						return err
+					}
+				case "insert":
+					if _, err := fmt.Fprintf(w, "-I %s %d %s\n", chain, o.pos, o.spec); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if _, err := fmt.Fprintln(w, "COMMIT"); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+)
+
+func mustParse(t *testing.T, data string) map[string]*iptables.Table {
+	t.Helper()
+	tables, err := iptables.Parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("iptables.Parse: %v", err)
+	}
+	return tables
+}
+
+func TestDiffAddedRule(t *testing.T) {
+	old := mustParse(t, `*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -p tcp --dport 22 -j ACCEPT
+COMMIT
+`)
+	new := mustParse(t, `*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -p tcp --dport 22 -j ACCEPT
+-A INPUT -p tcp --dport 80 -j ACCEPT
+COMMIT
+`)
+
+	var out strings.Builder
+	if err := Diff(old, new).Render(&out); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out.String(), "-A INPUT -p tcp --dport 80 -j ACCEPT") {
+		t.Fatalf("expected new rule to be appended, got:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "-D INPUT") {
+		t.Fatalf("did not expect any deletions, got:\n%s", out.String())
+	}
+}
+
+func TestDiffRemovedRule(t *testing.T) {
+	old := mustParse(t, `*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -p tcp --dport 22 -j ACCEPT
+-A INPUT -p tcp --dport 80 -j ACCEPT
+COMMIT
+`)
+	new := mustParse(t, `*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -p tcp --dport 22 -j ACCEPT
+COMMIT
+`)
+
+	var out strings.Builder
+	if err := Diff(old, new).Render(&out); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out.String(), "-D INPUT -p tcp --dport 80 -j ACCEPT") {
+		t.Fatalf("expected removed rule to be deleted, got:\n%s", out.String())
+	}
+}
+
+func TestDiffNoChangeIsEmpty(t *testing.T) {
+	snapshot := `*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -p tcp --dport 22 -j ACCEPT
+COMMIT
+`
+	old := mustParse(t, snapshot)
+	new := mustParse(t, snapshot)
+
+	var out strings.Builder
+	if err := Diff(old, new).Render(&out); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected no-op diff to render nothing, got:\n%s", out.String())
+	}
+}
+
+func TestDiffNewChainIsCreated(t *testing.T) {
+	old := mustParse(t, `*filter
+:INPUT ACCEPT [0:0]
+COMMIT
+`)
+	new := mustParse(t, `*filter
+:INPUT ACCEPT [0:0]
+:CUSTOM-CHAIN - [0:0]
+-A CUSTOM-CHAIN -j RETURN
+COMMIT
+`)
+
+	var out strings.Builder
+	if err := Diff(old, new).Render(&out); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out.String(), ":CUSTOM-CHAIN - [0:0]") {
+		t.Fatalf("expected new chain declaration, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "-A CUSTOM-CHAIN -j RETURN") {
+		t.Fatalf("expected new chain's rule to be added, got:\n%s", out.String())
+	}
+}
+
+func TestDiffPreservesOrderOfUnchangedRules(t *testing.T) {
+	old := mustParse(t, `*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -p tcp --dport 22 -j ACCEPT
+-A INPUT -p tcp --dport 443 -j ACCEPT
+COMMIT
+`)
+	// Insert a rule between the two existing ones.
+	new := mustParse(t, `*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -p tcp --dport 22 -j ACCEPT
+-A INPUT -p tcp --dport 80 -j ACCEPT
+-A INPUT -p tcp --dport 443 -j ACCEPT
+COMMIT
+`)
+
+	var out strings.Builder
+	if err := Diff(old, new).Render(&out); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out.String(), "-I INPUT 2 -p tcp --dport 80 -j ACCEPT") {
+		t.Fatalf("expected rule to be inserted at position 2, got:\n%s", out.String())
+	}
+}
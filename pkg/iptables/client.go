@@ -0,0 +1,28 @@
+package iptables
+
+import "io"
+
+// RestoreOptions controls how Client.Restore applies an iptables-restore
+// script.
+type RestoreOptions struct {
+	// NoFlush, when true, restores with "--noflush" so chains not present
+	// in the script are left untouched instead of being flushed first.
+	NoFlush bool
+}
+
+// Client is the set of iptables operations this package exposes,
+// implemented either by shelling out to the real iptables binaries
+// (execClient) or entirely in memory (FakeClient) for tests that don't
+// want to touch the host's netfilter state.
+type Client interface {
+	List(table string) ([]*Rule, error)
+	AppendUnique(table, chain string, spec ...string) error
+	Delete(table, chain string, spec ...string) error
+	Exists(table, chain string, spec ...string) (bool, error)
+	NewChain(table, chain string) error
+	DeleteChain(table, chain string) error
+	ClearChain(table, chain string) error
+	ChangePolicy(table, chain, policy string) error
+	Save(w io.Writer) error
+	Restore(r io.Reader, opts RestoreOptions) error
+}
@@ -0,0 +1,154 @@
+package iptables
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// execClient implements Client by shelling out to the iptables,
+// iptables-save, and iptables-restore binaries.
+type execClient struct {
+	iptablesPath string
+}
+
+// New returns a Client backed by the host's iptables binary.
+func New() (Client, error) {
+	path, err := exec.LookPath("iptables")
+	if err != nil {
+		return nil, fmt.Errorf("iptables: %w", err)
+	}
+	return &execClient{iptablesPath: path}, nil
+}
+
+func (c *execClient) run(args ...string) (string, error) {
+	cmd := exec.Command(c.iptablesPath, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("iptables %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// List returns every rule in table, across all of its chains.
+func (c *execClient) List(table string) ([]*Rule, error) {
+	out, err := c.run("-t", table, "-S")
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*Rule
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "-A ") {
+			continue
+		}
+		parts := strings.Fields(line)
+		rules = append(rules, &Rule{ChainName: parts[1], Rule: line})
+	}
+	return rules, nil
+}
+
+// AppendUnique appends spec to chain in table unless an equivalent rule is
+// already present.
+func (c *execClient) AppendUnique(table, chain string, spec ...string) error {
+	exists, err := c.Exists(table, chain, spec...)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	args := append([]string{"-t", table, "-A", chain}, spec...)
+	_, err = c.run(args...)
+	return err
+}
+
+// Delete removes the first rule in table/chain matching spec.
+func (c *execClient) Delete(table, chain string, spec ...string) error {
+	args := append([]string{"-t", table, "-D", chain}, spec...)
+	_, err := c.run(args...)
+	return err
+}
+
+// Exists reports whether a rule matching spec already exists in
+// table/chain.
+func (c *execClient) Exists(table, chain string, spec ...string) (bool, error) {
+	args := append([]string{"-t", table, "-C", chain}, spec...)
+	_, err := c.run(args...)
+	if err == nil {
+		return true, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// iptables -C exits 1 when the rule does not exist.
+		return false, nil
+	}
+	return false, err
+}
+
+// NewChain creates a new, empty chain in table.
+func (c *execClient) NewChain(table, chain string) error {
+	_, err := c.run("-t", table, "-N", chain)
+	return err
+}
+
+// DeleteChain removes an empty, non-built-in chain from table.
+func (c *execClient) DeleteChain(table, chain string) error {
+	_, err := c.run("-t", table, "-X", chain)
+	return err
+}
+
+// ClearChain removes every rule from chain without deleting the chain
+// itself.
+func (c *execClient) ClearChain(table, chain string) error {
+	_, err := c.run("-t", table, "-F", chain)
+	return err
+}
+
+// ChangePolicy sets the default policy of a built-in chain.
+func (c *execClient) ChangePolicy(table, chain, policy string) error {
+	_, err := c.run("-t", table, "-P", chain, policy)
+	return err
+}
+
+// Save writes the full iptables-save dump of every table to w.
+func (c *execClient) Save(w io.Writer) error {
+	path, err := exec.LookPath("iptables-save")
+	if err != nil {
+		return fmt.Errorf("iptables-save: %w", err)
+	}
+	cmd := exec.Command(path)
+	cmd.Stdout = w
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("iptables-save: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Restore applies an iptables-restore script read from r.
+func (c *execClient) Restore(r io.Reader, opts RestoreOptions) error {
+	path, err := exec.LookPath("iptables-restore")
+	if err != nil {
+		return fmt.Errorf("iptables-restore: %w", err)
+	}
+	var args []string
+	if opts.NoFlush {
+		args = append(args, "--noflush")
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = r
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("iptables-restore: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
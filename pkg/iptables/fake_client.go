@@ -0,0 +1,264 @@
+package iptables
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// builtinChains lists the chains every table comes with out of the box and
+// that can never be deleted, keyed by table name.
+var builtinChains = map[string][]string{
+	"filter": {"INPUT", "FORWARD", "OUTPUT"},
+	"nat":    {"PREROUTING", "INPUT", "OUTPUT", "POSTROUTING"},
+	"mangle": {"PREROUTING", "INPUT", "FORWARD", "OUTPUT", "POSTROUTING"},
+}
+
+// FakeClient is an in-memory Client, modeled on Kubernetes' kubelet
+// fake_iptables: it stores rules in the same Table/Chain/Rule structs the
+// text parser produces, so tests can exercise iptables-driven code without
+// root or a real netfilter stack.
+type FakeClient struct {
+	mu     sync.Mutex
+	tables map[string]*Table
+	calls  int
+}
+
+// NewFakeClient returns a FakeClient seeded with the standard filter, nat,
+// and mangle tables and their built-in chains.
+func NewFakeClient() *FakeClient {
+	fc := &FakeClient{tables: map[string]*Table{}}
+	for table, chains := range builtinChains {
+		t := &Table{Name: table}
+		for _, chain := range chains {
+			t.Chains = append(t.Chains, &Chain{Name: chain, Policy: "ACCEPT", Counter: Counters{}})
+		}
+		fc.tables[table] = t
+	}
+	return fc
+}
+
+// Calls returns the number of Client method calls made so far.
+func (fc *FakeClient) Calls() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.calls
+}
+
+func isBuiltinChain(table, chain string) bool {
+	for _, c := range builtinChains[table] {
+		if c == chain {
+			return true
+		}
+	}
+	return false
+}
+
+func (fc *FakeClient) chain(table, chain string) (*Table, *Chain, error) {
+	t, ok := fc.tables[table]
+	if !ok {
+		return nil, nil, fmt.Errorf("iptables: table %q does not exist", table)
+	}
+	for _, c := range t.Chains {
+		if c.Name == chain {
+			return t, c, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("iptables: chain %q does not exist in table %q", chain, table)
+}
+
+// List returns every rule in table, across all of its chains.
+func (fc *FakeClient) List(table string) ([]*Rule, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.calls++
+
+	t, ok := fc.tables[table]
+	if !ok {
+		return nil, fmt.Errorf("iptables: table %q does not exist", table)
+	}
+	rules := make([]*Rule, len(t.Rules))
+	copy(rules, t.Rules)
+	return rules, nil
+}
+
+// AppendUnique appends spec to chain in table unless a rule with the same
+// text is already present; rules are compared as literal strings, so a
+// differently written but equivalent spec counts as new.
+func (fc *FakeClient) AppendUnique(table, chain string, spec ...string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.calls++
+
+	t, c, err := fc.chain(table, chain)
+	if err != nil {
+		return err
+	}
+	if fc.ruleIndex(t, c.Name, spec) >= 0 {
+		return nil
+	}
+	t.Rules = append(t.Rules, &Rule{ChainName: c.Name, Rule: fc.ruleLine(c.Name, spec)})
+	return nil
+}
+
+// Delete removes the first rule in table/chain matching spec.
+func (fc *FakeClient) Delete(table, chain string, spec ...string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.calls++
+
+	t, _, err := fc.chain(table, chain)
+	if err != nil {
+		return err
+	}
+	idx := fc.ruleIndex(t, chain, spec)
+	if idx < 0 {
+		return fmt.Errorf("iptables: no matching rule found in chain %q of table %q", chain, table)
+	}
+	t.Rules = append(t.Rules[:idx], t.Rules[idx+1:]...)
+	return nil
+}
+
+// Exists reports whether a rule matching spec already exists in
+// table/chain.
+func (fc *FakeClient) Exists(table, chain string, spec ...string) (bool, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.calls++
+
+	t, _, err := fc.chain(table, chain)
+	if err != nil {
+		return false, err
+	}
+	return fc.ruleIndex(t, chain, spec) >= 0, nil
+}
+
+// NewChain creates a new, empty chain in table.
+func (fc *FakeClient) NewChain(table, chain string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.calls++
+
+	t, ok := fc.tables[table]
+	if !ok {
+		return fmt.Errorf("iptables: table %q does not exist", table)
+	}
+	for _, c := range t.Chains {
+		if c.Name == chain {
+			return fmt.Errorf("iptables: chain %q already exists in table %q", chain, table)
+		}
+	}
+	t.Chains = append(t.Chains, &Chain{Name: chain, Policy: "-", Counter: Counters{}})
+	return nil
+}
+
+// DeleteChain removes an empty, non-built-in chain from table.
+func (fc *FakeClient) DeleteChain(table, chain string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.calls++
+
+	if isBuiltinChain(table, chain) {
+		return fmt.Errorf("iptables: chain %q is built-in and cannot be deleted from table %q", chain, table)
+	}
+	t, _, err := fc.chain(table, chain)
+	if err != nil {
+		return err
+	}
+	for _, r := range t.Rules {
+		if r.ChainName == chain {
+			return fmt.Errorf("iptables: chain %q in table %q is not empty", chain, table)
+		}
+	}
+	for i, c := range t.Chains {
+		if c.Name == chain {
+			t.Chains = append(t.Chains[:i], t.Chains[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("iptables: chain %q does not exist in table %q", chain, table)
+}
+
+// ClearChain removes every rule from chain without deleting the chain
+// itself.
+func (fc *FakeClient) ClearChain(table, chain string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.calls++
+
+	t, _, err := fc.chain(table, chain)
+	if err != nil {
+		return err
+	}
+	var kept []*Rule
+	for _, r := range t.Rules {
+		if r.ChainName != chain {
+			kept = append(kept, r)
+		}
+	}
+	t.Rules = kept
+	return nil
+}
+
+// ChangePolicy sets the default policy of a built-in chain.
+func (fc *FakeClient) ChangePolicy(table, chain, policy string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.calls++
+
+	if !isBuiltinChain(table, chain) {
+		return fmt.Errorf("iptables: chain %q in table %q is not built-in, it has no policy", chain, table)
+	}
+	_, c, err := fc.chain(table, chain)
+	if err != nil {
+		return err
+	}
+	c.Policy = policy
+	return nil
+}
+
+// Save writes every table as an iptables-save dump to w.
+func (fc *FakeClient) Save(w io.Writer) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.calls++
+
+	Print(w, fc.tables, PrintOptions{WithCounters: true, WithComments: true})
+	return nil
+}
+
+// Restore replaces the tables named in the script read from r with its
+// contents, leaving tables it doesn't mention untouched. opts.NoFlush is
+// ignored: each named table is always replaced whole, so its chains and
+// rules not in the script are dropped.
+func (fc *FakeClient) Restore(r io.Reader, opts RestoreOptions) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.calls++
+
+	parsed, err := Parse(r)
+	if err != nil {
+		return err
+	}
+	for name, t := range parsed {
+		fc.tables[name] = t
+	}
+	return nil
+}
+
+// ruleLine renders a rule the same way iptables-save would.
+func (fc *FakeClient) ruleLine(chain string, spec []string) string {
+	return fmt.Sprintf("-A %s %s", chain, strings.Join(spec, " "))
+}
+
+// ruleIndex returns the index of the rule in chain matching spec, or -1.
+func (fc *FakeClient) ruleIndex(t *Table, chain string, spec []string) int {
+	line := fc.ruleLine(chain, spec)
+	for i, r := range t.Rules {
+		if r.ChainName == chain && r.Rule == line {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,76 @@
+package iptables
+
+import "testing"
+
+func TestFakeClientAppendUniqueAndExists(t *testing.T) {
+	fc := NewFakeClient()
+
+	if err := fc.AppendUnique("filter", "INPUT", "-p", "tcp", "--dport", "22", "-j", "ACCEPT"); err != nil {
+		t.Fatalf("AppendUnique: %v", err)
+	}
+	if err := fc.AppendUnique("filter", "INPUT", "-p", "tcp", "--dport", "22", "-j", "ACCEPT"); err != nil {
+		t.Fatalf("AppendUnique (duplicate): %v", err)
+	}
+
+	rules, err := fc.List("filter")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("expected AppendUnique to dedupe, got %d rules", len(rules))
+	}
+
+	exists, err := fc.Exists("filter", "INPUT", "-p", "tcp", "--dport", "22", "-j", "ACCEPT")
+	if err != nil {
+		t.Fatalf("Exists: %v", err)
+	}
+	if !exists {
+		t.Fatalf("expected rule to exist")
+	}
+}
+
+func TestFakeClientBuiltinChainsProtected(t *testing.T) {
+	fc := NewFakeClient()
+
+	if err := fc.DeleteChain("filter", "INPUT"); err == nil {
+		t.Fatalf("expected deleting a built-in chain to fail")
+	}
+	if err := fc.NewChain("filter", "INPUT"); err == nil {
+		t.Fatalf("expected creating an already-existing chain to fail")
+	}
+	if err := fc.ChangePolicy("filter", "CUSTOM", "DROP"); err == nil {
+		t.Fatalf("expected ChangePolicy on a non-existent chain to fail")
+	}
+}
+
+func TestFakeClientCustomChainLifecycle(t *testing.T) {
+	fc := NewFakeClient()
+
+	if err := fc.NewChain("filter", "MY-CHAIN"); err != nil {
+		t.Fatalf("NewChain: %v", err)
+	}
+	if err := fc.AppendUnique("filter", "MY-CHAIN", "-j", "RETURN"); err != nil {
+		t.Fatalf("AppendUnique: %v", err)
+	}
+	if err := fc.DeleteChain("filter", "MY-CHAIN"); err == nil {
+		t.Fatalf("expected deleting a non-empty chain to fail")
+	}
+	if err := fc.ClearChain("filter", "MY-CHAIN"); err != nil {
+		t.Fatalf("ClearChain: %v", err)
+	}
+	if err := fc.DeleteChain("filter", "MY-CHAIN"); err != nil {
+		t.Fatalf("DeleteChain: %v", err)
+	}
+}
+
+func TestFakeClientCallsCounter(t *testing.T) {
+	fc := NewFakeClient()
+	if fc.Calls() != 0 {
+		t.Fatalf("expected 0 calls initially, got %d", fc.Calls())
+	}
+	_ = fc.AppendUnique("filter", "INPUT", "-j", "ACCEPT")
+	_, _ = fc.List("filter")
+	if fc.Calls() != 2 {
+		t.Fatalf("expected 2 calls, got %d", fc.Calls())
+	}
+}
@@ -0,0 +1,29 @@
+package iptables
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestGoldenRoundTrip reads a real "iptables-save -c" capture, parses it,
+// and writes it back out, asserting byte-for-byte equality: counters,
+// comments, and preamble lines must all survive the round trip.
+func TestGoldenRoundTrip(t *testing.T) {
+	want, err := os.ReadFile("testdata/golden-iptables-save-c.txt")
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+
+	tables, err := Parse(bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var got bytes.Buffer
+	Print(&got, tables, PrintOptions{WithCounters: true, WithComments: true})
+
+	if got.String() != string(want) {
+		t.Fatalf("round trip mismatch:\n--- want ---\n%s\n--- got ---\n%s", want, got.String())
+	}
+}
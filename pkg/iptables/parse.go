@@ -0,0 +1,121 @@
+package iptables
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Parse reads iptables-save output from an io.Reader and returns a map of
+// tables keyed by table name. It understands both the default output and
+// "iptables-save -c" output, which prefixes each rule with a
+// "[packets:bytes]" counter.
+func Parse(r io.Reader) (map[string]*Table, error) {
+	tables := make(map[string]*Table)
+	var currentTable *Table
+	var lastTable *Table
+	var pendingComments []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "#"):
+			pendingComments = append(pendingComments, line)
+
+		case strings.HasPrefix(line, "*"):
+			tableName := line[1:]
+			currentTable = &Table{Name: tableName, PreambleComments: pendingComments}
+			pendingComments = nil
+			tables[tableName] = currentTable
+			lastTable = currentTable
+
+		case strings.HasPrefix(line, ":"):
+			if currentTable != nil {
+				parts := strings.Fields(line)
+				chain := &Chain{Name: parts[0][1:], Policy: parts[1], Counter: parseCounters(parts[2])}
+				currentTable.Chains = append(currentTable.Chains, chain)
+			}
+
+		case strings.HasPrefix(line, "["):
+			// "[packets:bytes] -A CHAIN ..." as emitted by "iptables-save -c".
+			if currentTable == nil {
+				continue
+			}
+			end := strings.Index(line, "]")
+			if end < 0 {
+				continue
+			}
+			counters := parseCounters(line[:end+1])
+			rest := strings.TrimSpace(line[end+1:])
+			if strings.HasPrefix(rest, "-") {
+				appendRule(currentTable, rest, counters)
+			}
+
+		case strings.HasPrefix(line, "-"):
+			if currentTable != nil {
+				appendRule(currentTable, line, Counters{})
+			}
+
+		case strings.HasPrefix(line, "COMMIT"):
+			currentTable = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading input: %w", err)
+	}
+
+	// Any comments left over at EOF are a trailing footer (e.g. "#
+	// Completed on ...") with no following table to attach to as a
+	// preamble; keep them on the last table seen instead of dropping them.
+	if len(pendingComments) > 0 && lastTable != nil {
+		lastTable.TrailingComments = pendingComments
+	}
+	return tables, nil
+}
+
+func appendRule(t *Table, line string, counters Counters) {
+	parts := strings.Fields(line)
+	t.Rules = append(t.Rules, &Rule{
+		ChainName: parts[1],
+		Rule:      line,
+		Packets:   counters.Packets,
+		Bytes:     counters.Bytes,
+		Comment:   extractComment(line),
+	})
+}
+
+// extractComment pulls the text out of a rule's `-m comment --comment
+// "..."` match, if present.
+func extractComment(line string) string {
+	const marker = `--comment "`
+	idx := strings.Index(line, marker)
+	if idx < 0 {
+		return ""
+	}
+	rest := line[idx+len(marker):]
+	end := strings.Index(rest, `"`)
+	if end < 0 {
+		return ""
+	}
+	return rest[:end]
+}
+
+// parseCounters parses a "[packets:bytes]" string into Counters.
+func parseCounters(s string) Counters {
+	s = strings.TrimPrefix(s, "[")
+	s = strings.TrimSuffix(s, "]")
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return Counters{}
+	}
+	packets, _ := strconv.ParseUint(parts[0], 10, 64)
+	bytes, _ := strconv.ParseUint(parts[1], 10, 64)
+	return Counters{Packets: packets, Bytes: bytes}
+}
@@ -0,0 +1,61 @@
+package iptables
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// PrintOptions controls how Print renders a snapshot.
+type PrintOptions struct {
+	// WithCounters prefixes each rule with its "[packets:bytes]" counters,
+	// matching "iptables-save -c" output.
+	WithCounters bool
+	// WithComments reproduces each table's PreambleComments and
+	// TrailingComments.
+	WithComments bool
+}
+
+// Print writes tables in iptables-restore format to w, in a deterministic
+// (alphabetical) order by table name.
+func Print(w io.Writer, tables map[string]*Table, opts PrintOptions) {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		table := tables[name]
+		if opts.WithComments {
+			for _, comment := range table.PreambleComments {
+				fmt.Fprintln(w, comment)
+			}
+		}
+
+		fmt.Fprintf(w, "*%s\n", table.Name)
+
+		for _, chain := range table.Chains {
+			fmt.Fprintf(w, ":%s %s %s\n", chain.Name, chain.Policy, chain.Counter)
+		}
+
+		for _, rule := range table.Rules {
+			if opts.WithCounters {
+				fmt.Fprintf(w, "[%d:%d] %s\n", rule.Packets, rule.Bytes, rule.Rule)
+			} else {
+				fmt.Fprintln(w, rule.Rule)
+			}
+		}
+
+		fmt.Fprintln(w, "COMMIT")
+		if opts.WithComments {
+			for _, comment := range table.TrailingComments {
+				fmt.Fprintln(w, comment)
+			}
+		} else {
+			// Without preamble comments to separate tables, add a blank
+			// line for readability, matching the tool's original output.
+			fmt.Fprintln(w)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Package iptables provides types and helpers for reading and writing
+// iptables-save/iptables-restore data.
+package iptables
+
+import "fmt"
+
+// Table represents an iptables table (e.g., filter, nat).
+type Table struct {
+	Name   string
+	Chains []*Chain
+	Rules  []*Rule
+
+	// PreambleComments holds the "#"-prefixed lines (e.g. "# Generated by
+	// iptables-save v1.8.7 on ...") that appeared immediately before this
+	// table's "*name" line, so they can be reproduced on output.
+	PreambleComments []string
+
+	// TrailingComments holds "#"-prefixed lines (e.g. "# Completed on ...")
+	// that appeared after this table's COMMIT with no subsequent table to
+	// attach to as a preamble, i.e. the dump's final footer.
+	TrailingComments []string
+}
+
+// Chain represents a single iptables chain within a table.
+type Chain struct {
+	Name    string
+	Policy  string // e.g., ACCEPT, DROP
+	Counter Counters
+}
+
+// Counters holds the packet/byte counters iptables-save reports for a
+// chain or, with "-c", for an individual rule.
+type Counters struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// String renders counters in the "[packets:bytes]" form iptables-save uses.
+func (c Counters) String() string {
+	return fmt.Sprintf("[%d:%d]", c.Packets, c.Bytes)
+}
+
+// Rule represents a single iptables rule.
+type Rule struct {
+	ChainName string
+	Rule      string // The full rule spec (e.g., "-A DOCKER-USER -j RETURN")
+
+	// Packets and Bytes are the per-rule counters emitted by
+	// "iptables-save -c" as a "[packets:bytes]" prefix.
+	Packets uint64
+	Bytes   uint64
+
+	// Comment is the text of this rule's "-m comment --comment" match, if
+	// any, extracted for convenience; the comment match itself remains
+	// part of Rule as well.
+	Comment string
+}
@@ -0,0 +1,56 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+)
+
+// PrefixCommentHandler adds a "-m comment --comment" match to every rule
+// that passes through, tagging it with comment. The match goes just ahead
+// of the rule's "-j"/"-g" target, or at the end if the rule has none.
+type PrefixCommentHandler struct {
+	next    Handler
+	comment string
+}
+
+// NewPrefixCommentHandler returns a handler that tags every rule with
+// comment and forwards it to next.
+func NewPrefixCommentHandler(comment string, next Handler) *PrefixCommentHandler {
+	return &PrefixCommentHandler{next: next, comment: comment}
+}
+
+func (h *PrefixCommentHandler) OnComment(line string) error {
+	return h.next.OnComment(line)
+}
+
+func (h *PrefixCommentHandler) OnTable(name string) error {
+	return h.next.OnTable(name)
+}
+
+func (h *PrefixCommentHandler) OnChain(chain *iptables.Chain) error {
+	return h.next.OnChain(chain)
+}
+
+func (h *PrefixCommentHandler) OnRule(rule *iptables.Rule) error {
+	// Matches belong before the target in the rule grammar, so splice the
+	// comment match in ahead of "-j"/"-g" instead of appending to the end.
+	// findJumpFlag is quote-aware, so a "-j"/"-g" that merely appears
+	// inside an existing quoted comment/log-prefix value isn't mistaken
+	// for the real jump flag.
+	insertion := fmt.Sprintf("-m comment --comment %q ", h.comment)
+	if flag, ok := findJumpFlag(rule.Rule); ok {
+		rule.Rule = rule.Rule[:flag.start] + insertion + rule.Rule[flag.start:]
+	} else {
+		rule.Rule += " " + insertion[:len(insertion)-1]
+	}
+
+	if rule.Comment == "" {
+		rule.Comment = h.comment
+	}
+	return h.next.OnRule(rule)
+}
+
+func (h *PrefixCommentHandler) OnCommit(table string) error {
+	return h.next.OnCommit(table)
+}
@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+	"github.com/masudur-rahman/iptables-parser/pkg/rule"
+)
+
+// DedupHandler suppresses rules that are semantically equal, per
+// rule.Rule.Canonical, to one already forwarded for the same table and
+// chain. A rule that fails to parse is always forwarded, since its
+// equivalence to anything else can't be determined.
+type DedupHandler struct {
+	next         Handler
+	currentTable string
+	seen         map[string]bool
+}
+
+// NewDedupHandler returns a handler that drops semantic duplicates and
+// forwards everything else to next.
+func NewDedupHandler(next Handler) *DedupHandler {
+	return &DedupHandler{next: next, seen: make(map[string]bool)}
+}
+
+func (h *DedupHandler) OnComment(line string) error {
+	return h.next.OnComment(line)
+}
+
+func (h *DedupHandler) OnTable(name string) error {
+	h.currentTable = name
+	return h.next.OnTable(name)
+}
+
+func (h *DedupHandler) OnChain(chain *iptables.Chain) error {
+	return h.next.OnChain(chain)
+}
+
+func (h *DedupHandler) OnRule(r *iptables.Rule) error {
+	parsed, err := rule.Parse(r.Rule)
+	if err != nil {
+		return h.next.OnRule(r)
+	}
+
+	key := h.currentTable + "|" + r.ChainName + "|" + parsed.Canonical()
+	if h.seen[key] {
+		return nil
+	}
+	h.seen[key] = true
+	return h.next.OnRule(r)
+}
+
+func (h *DedupHandler) OnCommit(table string) error {
+	return h.next.OnCommit(table)
+}
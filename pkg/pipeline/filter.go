@@ -0,0 +1,56 @@
+package pipeline
+
+import "github.com/masudur-rahman/iptables-parser/pkg/iptables"
+
+// FilterHandler drops chains and rules that don't belong to the requested
+// tables or chains before forwarding surviving events to next. An empty
+// tables or chains set allows everything for that dimension.
+type FilterHandler struct {
+	next    Handler
+	tables  map[string]bool
+	chains  map[string]bool
+	allowed bool
+}
+
+// NewFilterHandler returns a FilterHandler that forwards events for tables
+// in tables and chains in chains to next. A nil or empty set allows all.
+func NewFilterHandler(tables, chains map[string]bool, next Handler) *FilterHandler {
+	return &FilterHandler{next: next, tables: tables, chains: chains}
+}
+
+func (f *FilterHandler) OnComment(line string) error {
+	return f.next.OnComment(line)
+}
+
+func (f *FilterHandler) OnTable(name string) error {
+	f.allowed = len(f.tables) == 0 || f.tables[name]
+	if !f.allowed {
+		return nil
+	}
+	return f.next.OnTable(name)
+}
+
+func (f *FilterHandler) OnChain(chain *iptables.Chain) error {
+	if !f.allowed || !f.chainAllowed(chain.Name) {
+		return nil
+	}
+	return f.next.OnChain(chain)
+}
+
+func (f *FilterHandler) OnRule(rule *iptables.Rule) error {
+	if !f.allowed || !f.chainAllowed(rule.ChainName) {
+		return nil
+	}
+	return f.next.OnRule(rule)
+}
+
+func (f *FilterHandler) OnCommit(table string) error {
+	if !f.allowed {
+		return nil
+	}
+	return f.next.OnCommit(table)
+}
+
+func (f *FilterHandler) chainAllowed(name string) bool {
+	return len(f.chains) == 0 || f.chains[name]
+}
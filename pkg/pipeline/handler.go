@@ -0,0 +1,23 @@
+// Package pipeline provides a SAX-style, streaming reader for
+// iptables-save data: instead of buffering a whole dump into a
+// map[string]*iptables.Table before anything can run, a Parser emits
+// table/chain/rule/commit events to a Handler as it scans, so filters and
+// transforms can be chained and applied one line at a time.
+package pipeline
+
+import "github.com/masudur-rahman/iptables-parser/pkg/iptables"
+
+// Handler receives events from a Parser as it scans an iptables-save
+// stream. Handlers are typically chained: each one inspects or rewrites an
+// event and, unless it wants to drop the event, forwards it to a
+// downstream Handler.
+type Handler interface {
+	// OnComment is called for each "#"-prefixed line, in the order
+	// encountered (preamble before a table's "*name" line, or a trailing
+	// footer after a table's COMMIT with no following table).
+	OnComment(line string) error
+	OnTable(name string) error
+	OnChain(chain *iptables.Chain) error
+	OnRule(rule *iptables.Rule) error
+	OnCommit(table string) error
+}
@@ -0,0 +1,122 @@
+package pipeline
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+)
+
+// Parser scans iptables-save formatted text and emits events to a Handler
+// as it goes, without ever holding a full snapshot in memory.
+type Parser struct {
+	handler Handler
+}
+
+// NewParser returns a Parser that emits events to handler.
+func NewParser(handler Handler) *Parser {
+	return &Parser{handler: handler}
+}
+
+// Run scans r and emits OnTable/OnChain/OnRule/OnCommit events to the
+// Parser's Handler, stopping at the first error a handler returns.
+func (p *Parser) Run(r io.Reader) error {
+	var currentTable string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "#"):
+			if err := p.handler.OnComment(line); err != nil {
+				return err
+			}
+
+		case strings.HasPrefix(line, "*"):
+			currentTable = line[1:]
+			if err := p.handler.OnTable(currentTable); err != nil {
+				return err
+			}
+
+		case strings.HasPrefix(line, ":"):
+			parts := strings.Fields(line)
+			chain := &iptables.Chain{Name: parts[0][1:], Policy: parts[1], Counter: parseCounters(parts[2])}
+			if err := p.handler.OnChain(chain); err != nil {
+				return err
+			}
+
+		case strings.HasPrefix(line, "["):
+			end := strings.Index(line, "]")
+			if end < 0 {
+				continue
+			}
+			counters := parseCounters(line[:end+1])
+			rest := strings.TrimSpace(line[end+1:])
+			if !strings.HasPrefix(rest, "-") {
+				continue
+			}
+			if err := p.handler.OnRule(buildRule(rest, counters)); err != nil {
+				return err
+			}
+
+		case strings.HasPrefix(line, "-"):
+			if err := p.handler.OnRule(buildRule(line, iptables.Counters{})); err != nil {
+				return err
+			}
+
+		case strings.HasPrefix(line, "COMMIT"):
+			if err := p.handler.OnCommit(currentTable); err != nil {
+				return err
+			}
+			currentTable = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("pipeline: error reading input: %w", err)
+	}
+	return nil
+}
+
+func buildRule(line string, counters iptables.Counters) *iptables.Rule {
+	parts := strings.Fields(line)
+	return &iptables.Rule{
+		ChainName: parts[1],
+		Rule:      line,
+		Packets:   counters.Packets,
+		Bytes:     counters.Bytes,
+		Comment:   extractComment(line),
+	}
+}
+
+func extractComment(line string) string {
+	const marker = `--comment "`
+	idx := strings.Index(line, marker)
+	if idx < 0 {
+		return ""
+	}
+	rest := line[idx+len(marker):]
+	end := strings.Index(rest, `"`)
+	if end < 0 {
+		return ""
+	}
+	return rest[:end]
+}
+
+func parseCounters(s string) iptables.Counters {
+	s = strings.TrimPrefix(s, "[")
+	s = strings.TrimSuffix(s, "]")
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return iptables.Counters{}
+	}
+	packets, _ := strconv.ParseUint(parts[0], 10, 64)
+	bytes, _ := strconv.ParseUint(parts[1], 10, 64)
+	return iptables.Counters{Packets: packets, Bytes: bytes}
+}
@@ -0,0 +1,200 @@
+package pipeline
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+)
+
+const sampleInput = `*filter
+:INPUT ACCEPT [0:0]
+:OUTPUT ACCEPT [0:0]
+-A INPUT -i lo -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 22 -j ACCEPT
+-A INPUT -p tcp --dport 22 -j ACCEPT
+COMMIT
+`
+
+func run(t *testing.T, input string, handler Handler) {
+	t.Helper()
+	if err := NewParser(handler).Run(strings.NewReader(input)); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func TestParserEmitsEvents(t *testing.T) {
+	var out bytes.Buffer
+	run(t, sampleInput, NewPrintHandler(&out, iptables.PrintOptions{}))
+
+	want := `*filter
+:INPUT ACCEPT [0:0]
+:OUTPUT ACCEPT [0:0]
+-A INPUT -i lo -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 22 -j ACCEPT
+-A INPUT -p tcp --dport 22 -j ACCEPT
+COMMIT
+`
+	if out.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestFilterHandlerRestrictsChains(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewPrintHandler(&out, iptables.PrintOptions{})
+	filter := NewFilterHandler(nil, map[string]bool{"OUTPUT": true}, sink)
+	run(t, sampleInput, filter)
+
+	if strings.Contains(out.String(), "INPUT") {
+		t.Fatalf("expected INPUT to be filtered out, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), ":OUTPUT") {
+		t.Fatalf("expected OUTPUT chain to survive, got:\n%s", out.String())
+	}
+}
+
+func TestDedupHandlerSuppressesSemanticDuplicates(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewPrintHandler(&out, iptables.PrintOptions{})
+	run(t, sampleInput, NewDedupHandler(sink))
+
+	count := strings.Count(out.String(), "--dport 22")
+	if count != 1 {
+		t.Fatalf("expected the two equivalent --dport 22 rules to dedup to 1, got %d in:\n%s", count, out.String())
+	}
+}
+
+func TestPrintHandlerRoundTripsComments(t *testing.T) {
+	input := `# Generated by iptables-save v1.8.7 on Wed Jan  1 00:00:00 2025
+*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -i lo -j ACCEPT
+COMMIT
+# Completed on Wed Jan  1 00:00:00 2025
+`
+	var out bytes.Buffer
+	run(t, input, NewPrintHandler(&out, iptables.PrintOptions{WithComments: true}))
+
+	if out.String() != input {
+		t.Fatalf("got:\n%s\nwant:\n%s", out.String(), input)
+	}
+}
+
+func TestPrintHandlerDropsCommentsWithoutWithComments(t *testing.T) {
+	input := `# Generated by iptables-save v1.8.7 on Wed Jan  1 00:00:00 2025
+*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -i lo -j ACCEPT
+COMMIT
+`
+	var out bytes.Buffer
+	run(t, input, NewPrintHandler(&out, iptables.PrintOptions{}))
+
+	if strings.Contains(out.String(), "#") {
+		t.Fatalf("expected no comment lines without WithComments, got:\n%s", out.String())
+	}
+}
+
+func TestPrefixCommentHandlerInsertsBeforeJump(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewPrintHandler(&out, iptables.PrintOptions{})
+	tag := NewPrefixCommentHandler("tagged", sink)
+	run(t, sampleInput, tag)
+
+	for _, line := range strings.Split(out.String(), "\n") {
+		if !strings.HasPrefix(line, "-A") {
+			continue
+		}
+		commentAt := strings.Index(line, `--comment "tagged"`)
+		jumpAt := strings.Index(line, " -j ")
+		if commentAt < 0 || jumpAt < 0 || commentAt > jumpAt {
+			t.Fatalf("expected comment match before -j, got line: %q", line)
+		}
+	}
+}
+
+func TestPrefixCommentHandlerIgnoresJumpInsideQuotedValue(t *testing.T) {
+	input := `*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -m comment --comment "call -j now" -j ACCEPT
+COMMIT
+`
+	var out bytes.Buffer
+	sink := NewPrintHandler(&out, iptables.PrintOptions{})
+	run(t, input, NewPrefixCommentHandler("tagged", sink))
+
+	want := `-A INPUT -m comment --comment "call -j now" -m comment --comment "tagged" -j ACCEPT`
+	if !strings.Contains(out.String(), want) {
+		t.Fatalf("expected:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestRewriteJumpHandlerIgnoresTargetInsideQuotedValue(t *testing.T) {
+	input := `*filter
+:INPUT ACCEPT [0:0]
+-A INPUT -m comment --comment "ACCEPT everything" -j ACCEPT
+COMMIT
+`
+	var out bytes.Buffer
+	sink := NewPrintHandler(&out, iptables.PrintOptions{})
+	run(t, input, NewRewriteJumpHandler(map[string]string{"ACCEPT": "LOG,ACCEPT"}, sink))
+
+	want := `-A INPUT -m comment --comment "ACCEPT everything" -j LOG,ACCEPT`
+	if !strings.Contains(out.String(), want) {
+		t.Fatalf("expected:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestRewriteJumpHandlerRewritesTarget(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewPrintHandler(&out, iptables.PrintOptions{})
+	rewrite := NewRewriteJumpHandler(map[string]string{"ACCEPT": "LOG,ACCEPT"}, sink)
+	run(t, sampleInput, rewrite)
+
+	if strings.Contains(out.String(), "-j ACCEPT") {
+		t.Fatalf("expected ACCEPT targets to be rewritten, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "-j LOG,ACCEPT") {
+		t.Fatalf("expected rewritten target LOG,ACCEPT, got:\n%s", out.String())
+	}
+}
+
+func TestRenameChainHandlerRewritesRuleText(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewPrintHandler(&out, iptables.PrintOptions{})
+	rename := NewRenameChainHandler(map[string]string{"INPUT": "IN_NEW"}, sink)
+	run(t, sampleInput, rename)
+
+	if strings.Contains(out.String(), "-A INPUT ") {
+		t.Fatalf("expected all INPUT rule tokens to be renamed, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), ":IN_NEW") {
+		t.Fatalf("expected chain declaration to be renamed, got:\n%s", out.String())
+	}
+}
+
+func TestRenameChainHandlerRewritesJumpsFromOtherChains(t *testing.T) {
+	input := `*filter
+:FORWARD ACCEPT [0:0]
+:DOCKER-USER - [0:0]
+-A FORWARD -j DOCKER-USER
+-A DOCKER-USER -j RETURN
+COMMIT
+`
+	var out bytes.Buffer
+	sink := NewPrintHandler(&out, iptables.PrintOptions{})
+	rename := NewRenameChainHandler(map[string]string{"DOCKER-USER": "MY-CHAIN"}, sink)
+	run(t, input, rename)
+
+	if strings.Contains(out.String(), "DOCKER-USER") {
+		t.Fatalf("expected every reference to the renamed chain to be rewritten, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "-A FORWARD -j MY-CHAIN") {
+		t.Fatalf("expected FORWARD's jump target to be renamed, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), ":MY-CHAIN") || !strings.Contains(out.String(), "-A MY-CHAIN -j RETURN") {
+		t.Fatalf("expected MY-CHAIN's own declaration and rules to be renamed, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+)
+
+// PrintHandler writes events to w in iptables-restore format as they
+// arrive, rather than buffering a snapshot first.
+type PrintHandler struct {
+	w    io.Writer
+	opts iptables.PrintOptions
+}
+
+// NewPrintHandler returns a PrintHandler that writes to w.
+func NewPrintHandler(w io.Writer, opts iptables.PrintOptions) *PrintHandler {
+	return &PrintHandler{w: w, opts: opts}
+}
+
+func (p *PrintHandler) OnComment(line string) error {
+	if !p.opts.WithComments {
+		return nil
+	}
+	_, err := fmt.Fprintln(p.w, line)
+	return err
+}
+
+func (p *PrintHandler) OnTable(name string) error {
+	_, err := fmt.Fprintf(p.w, "*%s\n", name)
+	return err
+}
+
+func (p *PrintHandler) OnChain(chain *iptables.Chain) error {
+	_, err := fmt.Fprintf(p.w, ":%s %s %s\n", chain.Name, chain.Policy, chain.Counter)
+	return err
+}
+
+func (p *PrintHandler) OnRule(rule *iptables.Rule) error {
+	var err error
+	if p.opts.WithCounters {
+		_, err = fmt.Fprintf(p.w, "[%d:%d] %s\n", rule.Packets, rule.Bytes, rule.Rule)
+	} else {
+		_, err = fmt.Fprintln(p.w, rule.Rule)
+	}
+	return err
+}
+
+func (p *PrintHandler) OnCommit(table string) error {
+	_, err := fmt.Fprintln(p.w, "COMMIT")
+	return err
+}
@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+)
+
+// RenameChainHandler rewrites chain names as events pass through, both on
+// :CHAIN declarations and the chain token embedded in each rule's raw text
+// (e.g. "-A OLD ..." becomes "-A NEW ...").
+type RenameChainHandler struct {
+	next    Handler
+	renames map[string]string
+}
+
+// NewRenameChainHandler returns a handler that renames chains per renames
+// (old name -> new name) and forwards everything else unchanged to next.
+func NewRenameChainHandler(renames map[string]string, next Handler) *RenameChainHandler {
+	return &RenameChainHandler{next: next, renames: renames}
+}
+
+func (h *RenameChainHandler) OnComment(line string) error {
+	return h.next.OnComment(line)
+}
+
+func (h *RenameChainHandler) OnTable(name string) error {
+	return h.next.OnTable(name)
+}
+
+func (h *RenameChainHandler) OnChain(chain *iptables.Chain) error {
+	if renamed, ok := h.renames[chain.Name]; ok {
+		chain.Name = renamed
+	}
+	return h.next.OnChain(chain)
+}
+
+func (h *RenameChainHandler) OnRule(rule *iptables.Rule) error {
+	if renamed, ok := h.renames[rule.ChainName]; ok {
+		rule.Rule = replaceChainToken(rule.Rule, rule.ChainName, renamed)
+		rule.ChainName = renamed
+	}
+
+	// A rule in any chain may "-j"/"-g" into a chain that got renamed, not
+	// just rules that belong to that chain, so check the jump target too.
+	if target, ok := findJumpTarget(rule.Rule); ok {
+		if renamed, ok := h.renames[target.text]; ok {
+			rule.Rule = rule.Rule[:target.start] + renamed + rule.Rule[target.start+len(target.text):]
+		}
+	}
+
+	return h.next.OnRule(rule)
+}
+
+func (h *RenameChainHandler) OnCommit(table string) error {
+	return h.next.OnCommit(table)
+}
+
+// replaceChainToken replaces the "-A/-I/-D <chain>" token in line with
+// newChain, leaving the rest of the rule untouched. It's quote-aware via
+// tokenizeLine so a quoted match value elsewhere on the line (e.g. a
+// --comment that happens to contain oldChain) is never mistaken for it.
+func replaceChainToken(line, oldChain, newChain string) string {
+	tokens := tokenizeLine(line)
+	if len(tokens) < 2 || tokens[1].text != oldChain {
+		return line
+	}
+	t := tokens[1]
+	return line[:t.start] + newChain + line[t.start+len(t.text):]
+}
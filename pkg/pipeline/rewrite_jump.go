@@ -0,0 +1,47 @@
+package pipeline
+
+import (
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+)
+
+// RewriteJumpHandler rewrites the target of "-j"/"-g" on each rule per
+// mapping (old target -> new target), e.g. swapping "-j ACCEPT" for
+// "-j LOG,ACCEPT" to log matching packets before the original verdict.
+type RewriteJumpHandler struct {
+	next    Handler
+	mapping map[string]string
+}
+
+// NewRewriteJumpHandler returns a handler that rewrites jump/goto targets
+// per mapping and forwards everything else unchanged to next.
+func NewRewriteJumpHandler(mapping map[string]string, next Handler) *RewriteJumpHandler {
+	return &RewriteJumpHandler{next: next, mapping: mapping}
+}
+
+func (h *RewriteJumpHandler) OnComment(line string) error {
+	return h.next.OnComment(line)
+}
+
+func (h *RewriteJumpHandler) OnTable(name string) error {
+	return h.next.OnTable(name)
+}
+
+func (h *RewriteJumpHandler) OnChain(chain *iptables.Chain) error {
+	return h.next.OnChain(chain)
+}
+
+func (h *RewriteJumpHandler) OnRule(rule *iptables.Rule) error {
+	// findJumpTarget is quote-aware, so a "-j"/"-g" that merely appears
+	// inside a quoted comment/log-prefix value is never mistaken for the
+	// real jump flag or target.
+	if target, ok := findJumpTarget(rule.Rule); ok {
+		if replacement, ok := h.mapping[target.text]; ok {
+			rule.Rule = rule.Rule[:target.start] + replacement + rule.Rule[target.start+len(target.text):]
+		}
+	}
+	return h.next.OnRule(rule)
+}
+
+func (h *RewriteJumpHandler) OnCommit(table string) error {
+	return h.next.OnCommit(table)
+}
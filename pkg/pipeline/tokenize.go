@@ -0,0 +1,71 @@
+package pipeline
+
+// token is a single whitespace-delimited span of a raw rule line, with the
+// byte offset it starts at so callers can edit the line in place without
+// disturbing quoted values elsewhere on it.
+type token struct {
+	text  string
+	start int
+}
+
+// tokenizeLine splits line on whitespace, except that double-quoted spans
+// (e.g. a "--comment" value) are kept intact as a single token. This
+// mirrors pkg/rule's tokenizer so that a "-j"/"-g" or other bare word
+// inside a quoted, free-text match value is never mistaken for a real
+// flag.
+func tokenizeLine(line string) []token {
+	var tokens []token
+	inQuotes := false
+	start := -1
+
+	for i := 0; i <= len(line); i++ {
+		atEnd := i == len(line)
+		var c byte
+		if !atEnd {
+			c = line[i]
+		}
+
+		switch {
+		case !atEnd && c == '"':
+			inQuotes = !inQuotes
+		case atEnd || (c == ' ' && !inQuotes):
+			if start >= 0 {
+				tokens = append(tokens, token{text: line[start:i], start: start})
+				start = -1
+			}
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+
+	return tokens
+}
+
+// findJumpFlag returns the "-j"/"-g" token in line, and ok=false if there
+// is none.
+func findJumpFlag(line string) (flag token, ok bool) {
+	for _, t := range tokenizeLine(line) {
+		if t.text == "-j" || t.text == "-g" {
+			return t, true
+		}
+	}
+	return token{}, false
+}
+
+// findJumpTarget returns the token immediately following a "-j"/"-g" token
+// in line, and ok=false if there is none.
+func findJumpTarget(line string) (target token, ok bool) {
+	tokens := tokenizeLine(line)
+	for i, t := range tokens {
+		if t.text != "-j" && t.text != "-g" {
+			continue
+		}
+		if i+1 >= len(tokens) {
+			return token{}, false
+		}
+		return tokens[i+1], true
+	}
+	return token{}, false
+}
@@ -0,0 +1,93 @@
+package rule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// matchArg is a single flag/value pair inside a `-m <module>` match block,
+// e.g. `--dport 80` or `--ctstate ESTABLISHED,RELATED`.
+type matchArg struct {
+	Flag    string
+	Values  []string // comma-separated values split apart
+	Negated bool
+	// Opaque marks a flag whose argument is a free-text string (e.g.
+	// "--comment" or "--log-prefix") rather than a comma-delimited list, so
+	// it must not be split or reordered.
+	Opaque bool
+}
+
+// Match represents one `-m <module> ...` block attached to a rule, such as
+// `-m tcp --dport 80` or `-m conntrack --ctstate ESTABLISHED,RELATED`.
+type Match struct {
+	Module string
+	args   []matchArg
+}
+
+// equal reports whether two matches are semantically equivalent: same
+// module, same set of flags/values, regardless of the order flags were
+// given in or the order of comma-separated values within a flag.
+func (m Match) equal(other Match) bool {
+	if m.Module != other.Module {
+		return false
+	}
+	return sameArgSet(m.args, other.args)
+}
+
+func sameArgSet(a, b []matchArg) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return argKey(a) == argKey(b)
+}
+
+// argKey builds an order-independent string fingerprint for a set of match
+// args, suitable for equality comparison.
+func argKey(args []matchArg) string {
+	normalized := make([]string, len(args))
+	for i, a := range args {
+		values := a.Values
+		if !a.Opaque {
+			values = append([]string(nil), a.Values...)
+			sort.Strings(values)
+		}
+		neg := ""
+		if a.Negated {
+			neg = "!"
+		}
+		normalized[i] = fmt.Sprintf("%s%s=%s", neg, a.Flag, strings.Join(values, ","))
+	}
+	sort.Strings(normalized)
+	return strings.Join(normalized, "&")
+}
+
+// canonical renders the match in a stable, deterministic form:
+// `-m <module>` followed by its flags sorted by name.
+func (m Match) canonical() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-m %s", m.Module)
+
+	args := append([]matchArg(nil), m.args...)
+	sort.Slice(args, func(i, j int) bool { return args[i].Flag < args[j].Flag })
+
+	for _, a := range args {
+		if a.Negated {
+			b.WriteString(" !")
+		}
+		fmt.Fprintf(&b, " %s %s", a.Flag, formatArgValue(a))
+	}
+	return b.String()
+}
+
+// formatArgValue renders a matchArg's value(s) for Canonical output:
+// opaque free-text values are quoted and left as-is, while list-valued
+// flags are comma-joined after sorting for determinism.
+func formatArgValue(a matchArg) string {
+	if a.Opaque {
+		return fmt.Sprintf("%q", strings.Join(a.Values, ","))
+	}
+	values := append([]string(nil), a.Values...)
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
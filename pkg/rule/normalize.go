@@ -0,0 +1,71 @@
+package rule
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// longToShortFlag maps the long-form iptables flags this package understands
+// to their short-form equivalent so rules can be compared regardless of
+// which form the user wrote.
+var longToShortFlag = map[string]string{
+	"--append":           "-A",
+	"--insert":           "-I",
+	"--delete":           "-D",
+	"--protocol":         "-p",
+	"--source":           "-s",
+	"--destination":      "-d",
+	"--in-interface":     "-i",
+	"--out-interface":    "-o",
+	"--jump":             "-j",
+	"--goto":             "-g",
+	"--match":            "-m",
+	"--source-port":      "--sport",
+	"--destination-port": "--dport",
+}
+
+// canonicalFlag returns the short form of flag, if one is known.
+func canonicalFlag(flag string) string {
+	if short, ok := longToShortFlag[flag]; ok {
+		return short
+	}
+	return flag
+}
+
+// normalizeCIDR converts an address that may be expressed as "ip/mask" or a
+// bare "ip" into a canonical "ip/prefixlen" string so that "10.0.0.0/8" and
+// "10.0.0.0/255.0.0.0" compare equal.
+func normalizeCIDR(addr string) string {
+	if addr == "" {
+		return addr
+	}
+
+	ip, maskPart, hasSlash := strings.Cut(addr, "/")
+	if !hasSlash {
+		// iptables treats a bare address as an implicit /32 (IPv4) or /128
+		// (IPv6) host route, so "10.0.0.5" and "10.0.0.5/32" must normalize
+		// to the same string to compare equal.
+		if strings.Contains(addr, ":") {
+			return addr + "/128"
+		}
+		return addr + "/32"
+	}
+
+	// Already a prefix length (e.g. "8" or "24").
+	if !strings.Contains(maskPart, ".") {
+		return fmt.Sprintf("%s/%s", ip, maskPart)
+	}
+
+	mask := net.ParseIP(maskPart)
+	if mask == nil {
+		return addr
+	}
+	mask4 := mask.To4()
+	if mask4 == nil {
+		return addr
+	}
+
+	ones, _ := net.IPMask(mask4).Size()
+	return fmt.Sprintf("%s/%d", ip, ones)
+}
@@ -0,0 +1,448 @@
+// Package rule parses iptables rule lines (e.g. an iptables-save
+// "-A CHAIN ..." line, action and chain included) into a structured form
+// that can be compared for semantic equivalence and re-serialized
+// deterministically, instead of treating the rule as an opaque string.
+package rule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rule is the parsed representation of a single iptables rule.
+type Rule struct {
+	Action string // "-A", "-I", or "-D"
+	Chain  string
+	// InsertPos is the 1-based position argument to "-I <chain> <pos>",
+	// or 0 if the rule did not specify one.
+	InsertPos int
+
+	Protocol     string
+	Source       string
+	Destination  string
+	InInterface  string
+	OutInterface string
+	SourcePort   string
+	DestPort     string
+	// Negated records which of the core flags above were prefixed with "!".
+	Negated map[string]bool
+
+	Matches []Match
+
+	Target     string
+	Goto       bool // true if the target was introduced with -g instead of -j
+	TargetArgs []matchArg
+}
+
+// coreFlags is the set of canonical short flags that fill in one of the
+// Rule's core fields (see setCore).
+var coreFlags = map[string]bool{
+	"-p": true, "-s": true, "-d": true,
+	"-i": true, "-o": true,
+	"--sport": true, "--dport": true,
+}
+
+// opaqueValueFlags holds flags whose argument is a free-text string rather
+// than a comma-delimited list, so it must never be split on "," or
+// reordered when comparing/canonicalizing (e.g. "-m comment --comment
+// 'allow web, and db'" or "-j LOG --log-prefix 'DROP, logged: '").
+var opaqueValueFlags = map[string]bool{
+	"--comment":    true,
+	"--log-prefix": true,
+}
+
+// Parse parses a single iptables rule specification, e.g.
+// "-A INPUT -p tcp -m tcp --dport 80 -j ACCEPT", into a *Rule.
+func Parse(line string) (*Rule, error) {
+	tokens := tokenize(line)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("rule: empty rule line")
+	}
+
+	r := &Rule{Negated: map[string]bool{}}
+
+	action := canonicalFlag(tokens[0])
+	switch action {
+	case "-A", "-I", "-D":
+		r.Action = action
+	default:
+		return nil, fmt.Errorf("rule: unsupported action %q", tokens[0])
+	}
+	tokens = tokens[1:]
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("rule: missing chain name")
+	}
+	r.Chain = tokens[0]
+	tokens = tokens[1:]
+
+	// "-I CHAIN <pos>" takes an optional bare numeric position next.
+	if r.Action == "-I" && len(tokens) > 0 && isUint(tokens[0]) {
+		fmt.Sscanf(tokens[0], "%d", &r.InsertPos)
+		tokens = tokens[1:]
+	}
+
+	if err := r.parseSpec(tokens); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Rule) parseSpec(tokens []string) error {
+	var negateNext bool
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+
+		if tok == "!" {
+			negateNext = true
+			continue
+		}
+
+		flag := canonicalFlag(tok)
+
+		switch flag {
+		case "-p", "-s", "-d", "-i", "-o", "--sport", "--dport":
+			if i+1 >= len(tokens) {
+				return fmt.Errorf("rule: flag %s requires an argument", flag)
+			}
+			i++
+			value := tokens[i]
+			if flag == "-s" || flag == "-d" {
+				value = normalizeCIDR(value)
+			}
+			r.setCore(flag, value)
+			if negateNext {
+				r.Negated[flag] = true
+			}
+
+		case "-m":
+			if i+1 >= len(tokens) {
+				return fmt.Errorf("rule: -m requires a module name")
+			}
+			i++
+			module := tokens[i]
+			consumed, args := parseMatchArgs(tokens[i+1:])
+			i += consumed
+			r.addMatch(module, args)
+
+		case "-j", "-g":
+			if i+1 >= len(tokens) {
+				return fmt.Errorf("rule: %s requires a target", flag)
+			}
+			i++
+			r.Target = tokens[i]
+			r.Goto = flag == "-g"
+			consumed, args := parseMatchArgs(tokens[i+1:])
+			i += consumed
+			r.TargetArgs = args
+
+		default:
+			// Unrecognized flag: skip it along with a following value, if
+			// any, so unknown extensions don't break the whole parse.
+			if i+1 < len(tokens) && !strings.HasPrefix(tokens[i+1], "-") {
+				i++
+			}
+		}
+
+		negateNext = false
+	}
+
+	// An implicit "-m tcp"/"-m udp" that contributes nothing beyond what
+	// -p/--sport/--dport already captured is redundant information; drop it
+	// so "-p tcp --dport 80" and "-p tcp -m tcp --dport 80" parse alike.
+	var kept []Match
+	for _, m := range r.Matches {
+		if m.Module == r.Protocol && len(m.args) == 0 {
+			continue
+		}
+		kept = append(kept, m)
+	}
+	r.Matches = kept
+
+	return nil
+}
+
+func (r *Rule) setCore(flag, value string) {
+	switch flag {
+	case "-p":
+		r.Protocol = value
+	case "-s":
+		r.Source = value
+	case "-d":
+		r.Destination = value
+	case "-i":
+		r.InInterface = value
+	case "-o":
+		r.OutInterface = value
+	case "--sport":
+		r.SourcePort = value
+	case "--dport":
+		r.DestPort = value
+	}
+}
+
+// addMatch records a -m module invocation, folding --sport/--dport args
+// (which double as core fields for tcp/udp) up into the Rule's core fields
+// and keeping only the remaining args on the Match itself.
+func (r *Rule) addMatch(module string, args []matchArg) {
+	var remaining []matchArg
+	for _, a := range args {
+		flag := canonicalFlag(a.Flag)
+		if coreFlags[flag] && len(a.Values) == 1 {
+			r.setCore(flag, a.Values[0])
+			if a.Negated {
+				r.Negated[flag] = true
+			}
+			continue
+		}
+		remaining = append(remaining, a)
+	}
+
+	for i, existing := range r.Matches {
+		if existing.Module == module {
+			r.Matches[i].args = append(r.Matches[i].args, remaining...)
+			return
+		}
+	}
+	r.Matches = append(r.Matches, Match{Module: module, args: remaining})
+}
+
+// parseMatchArgs consumes "--flag value" pairs (with optional leading "!")
+// until it hits another top-level flag (-m, -j, -g) or runs out of tokens.
+// It returns how many tokens were consumed and the parsed args.
+func parseMatchArgs(tokens []string) (int, []matchArg) {
+	var args []matchArg
+	var negateNext bool
+	consumed := 0
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok == "!" {
+			negateNext = true
+			consumed++
+			continue
+		}
+		if tok == "-m" || tok == "--match" || tok == "-j" || tok == "--jump" || tok == "-g" || tok == "--goto" {
+			break
+		}
+		if !strings.HasPrefix(tok, "-") {
+			// Stray value with no flag; stop rather than misparse.
+			break
+		}
+
+		flag := canonicalFlag(tok)
+		consumed++
+		if i+1 < len(tokens) && !strings.HasPrefix(tokens[i+1], "-") {
+			i++
+			consumed++
+			opaque := opaqueValueFlags[flag]
+			values := []string{tokens[i]}
+			if !opaque {
+				values = strings.Split(tokens[i], ",")
+			}
+			args = append(args, matchArg{
+				Flag:    flag,
+				Values:  values,
+				Negated: negateNext,
+				Opaque:  opaque,
+			})
+		} else {
+			// Boolean flag with no value (e.g. "--syn").
+			args = append(args, matchArg{Flag: flag, Negated: negateNext})
+		}
+		negateNext = false
+	}
+
+	return consumed, args
+}
+
+// Equal reports whether r and other specify the same rule, regardless of
+// flag order, short vs. long flag form, or IP/mask notation.
+func (r *Rule) Equal(other *Rule) bool {
+	if other == nil {
+		return false
+	}
+	if r.Action != other.Action ||
+		r.Chain != other.Chain ||
+		r.Protocol != other.Protocol ||
+		r.Source != other.Source ||
+		r.Destination != other.Destination ||
+		r.InInterface != other.InInterface ||
+		r.OutInterface != other.OutInterface ||
+		r.SourcePort != other.SourcePort ||
+		r.DestPort != other.DestPort ||
+		r.Target != other.Target ||
+		r.Goto != other.Goto {
+		return false
+	}
+
+	// InsertPos only matters for -I: it's the position a rule is inserted
+	// at, not part of what the rule matches or does, so two -A/-D rules
+	// with the same spec are still equal regardless of any stray position
+	// value either might carry.
+	if r.Action == "-I" && r.InsertPos != other.InsertPos {
+		return false
+	}
+
+	if !sameNegations(r.Negated, other.Negated) {
+		return false
+	}
+
+	if argKey(r.TargetArgs) != argKey(other.TargetArgs) {
+		return false
+	}
+
+	return sameMatchSet(r.Matches, other.Matches)
+}
+
+func sameNegations(a, b map[string]bool) bool {
+	normalize := func(m map[string]bool) map[string]bool {
+		out := map[string]bool{}
+		for k, v := range m {
+			if v {
+				out[k] = true
+			}
+		}
+		return out
+	}
+	na, nb := normalize(a), normalize(b)
+	if len(na) != len(nb) {
+		return false
+	}
+	for k := range na {
+		if !nb[k] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameMatchSet(a, b []Match) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	used := make([]bool, len(b))
+	for _, ma := range a {
+		found := false
+		for i, mb := range b {
+			if used[i] {
+				continue
+			}
+			if ma.equal(mb) {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Canonical renders the rule in a stable, deterministic form suitable for
+// hashing or diffing: flags always appear in the same order and matches are
+// sorted by module name.
+func (r *Rule) Canonical() string {
+	var b strings.Builder
+	b.WriteString(r.Action)
+	b.WriteByte(' ')
+	b.WriteString(r.Chain)
+
+	if r.Action == "-I" && r.InsertPos > 0 {
+		fmt.Fprintf(&b, " %d", r.InsertPos)
+	}
+
+	writeNegatable := func(flag, value string) {
+		if value == "" {
+			return
+		}
+		if r.Negated[flag] {
+			b.WriteString(" !")
+		}
+		fmt.Fprintf(&b, " %s %s", flag, value)
+	}
+
+	writeNegatable("-p", r.Protocol)
+	writeNegatable("-s", r.Source)
+	writeNegatable("-d", r.Destination)
+	writeNegatable("-i", r.InInterface)
+	writeNegatable("-o", r.OutInterface)
+	writeNegatable("--sport", r.SourcePort)
+	writeNegatable("--dport", r.DestPort)
+
+	matches := append([]Match(nil), r.Matches...)
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Module < matches[j].Module })
+	for _, m := range matches {
+		b.WriteByte(' ')
+		b.WriteString(m.canonical())
+	}
+
+	if r.Target != "" {
+		jump := "-j"
+		if r.Goto {
+			jump = "-g"
+		}
+		fmt.Fprintf(&b, " %s %s", jump, r.Target)
+
+		args := append([]matchArg(nil), r.TargetArgs...)
+		sort.Slice(args, func(i, j int) bool { return args[i].Flag < args[j].Flag })
+		for _, a := range args {
+			if a.Negated {
+				b.WriteString(" !")
+			}
+			fmt.Fprintf(&b, " %s %s", a.Flag, formatArgValue(a))
+		}
+	}
+
+	return b.String()
+}
+
+// String implements fmt.Stringer by returning the canonical form.
+func (r *Rule) String() string {
+	return r.Canonical()
+}
+
+func isUint(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenize splits a rule line on whitespace while keeping double-quoted
+// substrings (e.g. `--comment "some text"`) intact as a single token.
+func tokenize(line string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuotes := false
+
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+
+	for _, c := range line {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ' ' && !inQuotes:
+			flush()
+		default:
+			cur.WriteRune(c)
+		}
+	}
+	flush()
+
+	return tokens
+}
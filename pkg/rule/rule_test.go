@@ -0,0 +1,197 @@
+package rule
+
+import "testing"
+
+func TestParseBasic(t *testing.T) {
+	r, err := Parse(`-A INPUT -p tcp -m tcp --dport 80 -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if r.Chain != "INPUT" || r.Protocol != "tcp" || r.DestPort != "80" || r.Target != "ACCEPT" {
+		t.Fatalf("unexpected rule: %+v", r)
+	}
+	if len(r.Matches) != 0 {
+		t.Fatalf("expected implicit tcp match to fold into core fields, got %+v", r.Matches)
+	}
+}
+
+func TestEqualIgnoresFlagOrderAndForm(t *testing.T) {
+	a, err := Parse(`-A INPUT --protocol tcp --dport 80 -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse a: %v", err)
+	}
+	b, err := Parse(`-A INPUT -p tcp -m tcp --dport 80 -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse b: %v", err)
+	}
+	if !a.Equal(b) {
+		t.Fatalf("expected %q and %q to be equal, got a=%+v b=%+v", a, b, a, b)
+	}
+}
+
+func TestEqualNormalizesIPMask(t *testing.T) {
+	a, err := Parse(`-A FORWARD -s 10.0.0.0/8 -j DROP`)
+	if err != nil {
+		t.Fatalf("Parse a: %v", err)
+	}
+	b, err := Parse(`-A FORWARD -s 10.0.0.0/255.0.0.0 -j DROP`)
+	if err != nil {
+		t.Fatalf("Parse b: %v", err)
+	}
+	if !a.Equal(b) {
+		t.Fatalf("expected %q and %q to be equal", a, b)
+	}
+}
+
+func TestEqualNormalizesImplicitHostMask(t *testing.T) {
+	a, err := Parse(`-A FORWARD -s 10.0.0.5 -j DROP`)
+	if err != nil {
+		t.Fatalf("Parse a: %v", err)
+	}
+	b, err := Parse(`-A FORWARD -s 10.0.0.5/32 -j DROP`)
+	if err != nil {
+		t.Fatalf("Parse b: %v", err)
+	}
+	if !a.Equal(b) {
+		t.Fatalf("expected %q and %q to be equal", a, b)
+	}
+
+	v6a, err := Parse(`-A FORWARD -s ::1 -j DROP`)
+	if err != nil {
+		t.Fatalf("Parse v6a: %v", err)
+	}
+	v6b, err := Parse(`-A FORWARD -s ::1/128 -j DROP`)
+	if err != nil {
+		t.Fatalf("Parse v6b: %v", err)
+	}
+	if !v6a.Equal(v6b) {
+		t.Fatalf("expected %q and %q to be equal", v6a, v6b)
+	}
+}
+
+func TestEqualMultiValueOrderIndependent(t *testing.T) {
+	a, err := Parse(`-A INPUT -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse a: %v", err)
+	}
+	b, err := Parse(`-A INPUT -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse b: %v", err)
+	}
+	if !a.Equal(b) {
+		t.Fatalf("expected %q and %q to be equal", a, b)
+	}
+}
+
+func TestNotEqualDifferentTarget(t *testing.T) {
+	a, err := Parse(`-A INPUT -p tcp --dport 80 -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse a: %v", err)
+	}
+	b, err := Parse(`-A INPUT -p tcp --dport 80 -j DROP`)
+	if err != nil {
+		t.Fatalf("Parse b: %v", err)
+	}
+	if a.Equal(b) {
+		t.Fatalf("expected rules with different targets to differ")
+	}
+}
+
+func TestNotEqualDifferentAction(t *testing.T) {
+	a, err := Parse(`-A INPUT -p tcp --dport 80 -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse a: %v", err)
+	}
+	b, err := Parse(`-D INPUT -p tcp --dport 80 -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse b: %v", err)
+	}
+	if a.Equal(b) {
+		t.Fatalf("expected -A and -D rules with the same spec to differ")
+	}
+}
+
+func TestEqualIgnoresInsertPosForNonInsert(t *testing.T) {
+	a, err := Parse(`-A INPUT -p tcp --dport 80 -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse a: %v", err)
+	}
+	b, err := Parse(`-A INPUT -p tcp --dport 80 -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse b: %v", err)
+	}
+	b.InsertPos = 3
+	if !a.Equal(b) {
+		t.Fatalf("expected InsertPos to be ignored for non -I rules")
+	}
+}
+
+func TestNotEqualDifferentInsertPos(t *testing.T) {
+	a, err := Parse(`-I INPUT 1 -p tcp --dport 80 -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse a: %v", err)
+	}
+	b, err := Parse(`-I INPUT 2 -p tcp --dport 80 -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse b: %v", err)
+	}
+	if a.Equal(b) {
+		t.Fatalf("expected -I rules inserted at different positions to differ")
+	}
+}
+
+func TestCanonicalIsOrderStable(t *testing.T) {
+	a, err := Parse(`-A INPUT -m tcp --dport 80 -p tcp -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse a: %v", err)
+	}
+	b, err := Parse(`-A INPUT -p tcp --dport 80 -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse b: %v", err)
+	}
+	if a.Canonical() != b.Canonical() {
+		t.Fatalf("expected canonical forms to match: %q vs %q", a.Canonical(), b.Canonical())
+	}
+}
+
+func TestCommentValueNotSplitOrReordered(t *testing.T) {
+	r, err := Parse(`-A INPUT -m comment --comment "allow web, and db" -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(r.Matches) != 1 || len(r.Matches[0].args) != 1 {
+		t.Fatalf("unexpected matches: %+v", r.Matches)
+	}
+	arg := r.Matches[0].args[0]
+	if len(arg.Values) != 1 || arg.Values[0] != "allow web, and db" {
+		t.Fatalf("expected comment text to survive intact, got %+v", arg.Values)
+	}
+
+	a, err := Parse(`-A INPUT -m comment --comment "allow web, and db" -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse a: %v", err)
+	}
+	b, err := Parse(`-A INPUT -m comment --comment "and db, allow web" -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("Parse b: %v", err)
+	}
+	if a.Equal(b) {
+		t.Fatalf("expected differently-worded comments not to compare equal")
+	}
+}
+
+func TestTargetArgsAndNegation(t *testing.T) {
+	r, err := Parse(`-A PREROUTING -p tcp ! -s 10.0.0.0/8 -j DNAT --to-destination 192.168.1.1:8080`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if !r.Negated["-s"] {
+		t.Fatalf("expected -s to be negated")
+	}
+	if r.Target != "DNAT" {
+		t.Fatalf("expected target DNAT, got %s", r.Target)
+	}
+	if len(r.TargetArgs) != 1 || r.TargetArgs[0].Flag != "--to-destination" {
+		t.Fatalf("unexpected target args: %+v", r.TargetArgs)
+	}
+}
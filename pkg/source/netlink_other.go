@@ -0,0 +1,25 @@
+//go:build !linux
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+)
+
+// NetlinkSource is unavailable outside Linux; ip_tables/nftables netlink
+// sockets are a Linux-only kernel interface.
+type NetlinkSource struct{}
+
+// NewNetlinkSource always fails on non-Linux platforms.
+func NewNetlinkSource() (*NetlinkSource, error) {
+	return nil, fmt.Errorf("source: netlink source is not supported on %s", runtime.GOOS)
+}
+
+// Read implements Source. It always fails: construction already did.
+func (s *NetlinkSource) Read(ctx context.Context) (map[string]*iptables.Table, error) {
+	return nil, fmt.Errorf("source: netlink source is not supported on %s", runtime.GOOS)
+}
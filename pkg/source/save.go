@@ -0,0 +1,30 @@
+package source
+
+import (
+	"context"
+	"io"
+
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+)
+
+// SaveSource reads a snapshot from iptables-save formatted text, e.g. a
+// file produced by "iptables-save" or "iptables-save -c". It's the
+// Source implementation for callers that want a full, buffered snapshot
+// (e.g. to feed pkg/diff); the CLI's own "--source=save" path reads the
+// same text through pkg/pipeline's streaming Parser instead, so a large
+// dump never has to be held in memory at once.
+type SaveSource struct {
+	r io.Reader
+}
+
+// NewSaveSource returns a Source that parses iptables-save output read
+// from r.
+func NewSaveSource(r io.Reader) *SaveSource {
+	return &SaveSource{r: r}
+}
+
+// Read implements Source. ctx is accepted for symmetry with other sources
+// but is unused: parsing an in-memory reader has nothing to cancel.
+func (s *SaveSource) Read(ctx context.Context) (map[string]*iptables.Table, error) {
+	return iptables.Parse(s.r)
+}
@@ -0,0 +1,15 @@
+// Package source abstracts where a full iptables snapshot comes from, so
+// callers can read one from an iptables-save dump or straight from the
+// kernel without caring which.
+package source
+
+import (
+	"context"
+
+	"github.com/masudur-rahman/iptables-parser/pkg/iptables"
+)
+
+// Source reads a complete iptables snapshot.
+type Source interface {
+	Read(ctx context.Context) (map[string]*iptables.Table, error)
+}